@@ -0,0 +1,92 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package externalbuilder
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// extractArchive unpacks the gzip-compressed tarball at archivePath into
+// dest, preserving the executable bit on builder scripts.
+func extractArchive(archivePath, dest string) error {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return errors.WithMessage(err, "builder archive is not a valid gzip stream")
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.WithMessage(err, "could not read builder archive")
+		}
+
+		target, err := safeJoin(dest, header.Name)
+		if err != nil {
+			return err
+		}
+
+		if header.Typeflag == tar.TypeSymlink || header.Typeflag == tar.TypeLink {
+			return errors.Errorf("builder archive entry '%s' is a link, which is not permitted", header.Name)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode))
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(out, tr); err != nil {
+				out.Close()
+				return err
+			}
+			out.Close()
+		}
+	}
+
+	return os.Remove(archivePath)
+}
+
+// safeJoin joins dest and name the way extractArchive needs to for tar
+// entries: it rejects any name that, once cleaned, would resolve outside
+// of dest (a "../" traversal), which a hostile registry could otherwise use
+// to write to arbitrary paths on the host (Zip Slip). An absolute name is
+// not rejected; it is placed beneath dest like a relative one.
+func safeJoin(dest, name string) (string, error) {
+	target := filepath.Join(dest, name)
+
+	cleanDest := filepath.Clean(dest)
+	if target != cleanDest && !strings.HasPrefix(target, cleanDest+string(os.PathSeparator)) {
+		return "", errors.Errorf("builder archive entry '%s' escapes destination directory", name)
+	}
+
+	return target, nil
+}
@@ -0,0 +1,89 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package externalbuilder_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/hyperledger/fabric/core/container/externalbuilder"
+)
+
+// maliciousArchive builds a gzip-compressed tarball whose single entry
+// attempts to traverse outside of the directory it will be extracted into.
+func maliciousArchive() []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	contents := []byte("#!/bin/sh\necho pwned\n")
+	Expect(tw.WriteHeader(&tar.Header{
+		Name:     "../../../../tmp/fabric-ziptest-pwned",
+		Typeflag: tar.TypeReg,
+		Mode:     0o755,
+		Size:     int64(len(contents)),
+	})).To(Succeed())
+	_, err := tw.Write(contents)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(tw.Close()).To(Succeed())
+	Expect(gz.Close()).To(Succeed())
+
+	return buf.Bytes()
+}
+
+var _ = Describe("Registry archive extraction", func() {
+	It("rejects a tar entry that traverses outside the destination directory", func() {
+		archive := maliciousArchive()
+		sum := sha256.Sum256(archive)
+		checksum := hex.EncodeToString(sum[:])
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("/archive.tar.gz", func(w http.ResponseWriter, r *http.Request) {
+			w.Write(archive)
+		})
+
+		var server *httptest.Server
+		mux.HandleFunc("/manifest", func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode([]externalbuilder.Manifest{
+				{
+					Name:     "node-builder",
+					Version:  "1.2.3",
+					Type:     "node",
+					URL:      server.URL + "/archive.tar.gz",
+					Checksum: checksum,
+				},
+			})
+		})
+		server = httptest.NewServer(mux)
+		defer server.Close()
+
+		cacheDir := GinkgoT().TempDir()
+		registry := &externalbuilder.Registry{
+			Endpoint:      server.URL + "/manifest",
+			CacheDir:      cacheDir,
+			AllowUnsigned: true,
+		}
+
+		_, err := registry.Resolve("node-builder", "node")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("escapes destination directory"))
+
+		_, statErr := os.Stat(filepath.Join(os.TempDir(), "fabric-ziptest-pwned"))
+		Expect(os.IsNotExist(statErr)).To(BeTrue())
+	})
+})
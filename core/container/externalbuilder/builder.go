@@ -0,0 +1,101 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package externalbuilder
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/core/container/ccintf"
+	"github.com/pkg/errors"
+)
+
+// Builder represents an external builder as configured via core.yaml's
+// externalBuilders list: a name and the path on disk where its detect,
+// build, release and run scripts live.
+type Builder struct {
+	Name     string
+	Location string
+	Logger   *flogging.FabricLogger
+
+	// Registry is optional. When set, Location is resolved and populated on
+	// demand by fetching and verifying the named builder from a configured
+	// marketplace registry instead of requiring it to already be present on
+	// disk.
+	Registry *Registry
+
+	// Sandbox is optional. When set, every invocation of the builder's
+	// scripts is launched under the configured resource and syscall
+	// restrictions instead of running with the peer's own privileges.
+	Sandbox *Sandbox
+
+	// LogFormatJSON mirrors the build-config "log-format: json" opt-in: when
+	// set, the builder's stdout/stderr are parsed as line-delimited JSON
+	// instead of treated as opaque byte streams.
+	LogFormatJSON bool
+
+	// LogSink, if set, receives every structured line captured from the
+	// builder's run invocation in addition to the normal peer log.
+	LogSink LogSink
+}
+
+// Resolve ensures the builder's scripts are present at Location, fetching
+// them from Registry first when the builder isn't already materialized
+// locally. It returns the resolved version string for logging purposes.
+func (b *Builder) Resolve(ccType string) (string, error) {
+	if b.Registry == nil {
+		return "", nil
+	}
+
+	resolved, err := b.Registry.Resolve(b.Name, ccType)
+	if err != nil {
+		return "", errors.WithMessage(err, fmt.Sprintf("builder '%s' could not be resolved", b.Name))
+	}
+
+	b.Location = resolved.Path
+	return resolved.Version, nil
+}
+
+// Run starts the builder's "run" script for the given chaincode package and
+// connects it to the peer described by peerConnection.
+func (b *Builder) Run(packageID string, peerConnection *ccintf.PeerConnection) (*Session, error) {
+	if version, err := b.Resolve(""); err != nil {
+		return nil, err
+	} else if version != "" {
+		b.Logger.Infow("resolved external builder", "name", b.Name, "version", version)
+	}
+
+	runCmd, err := b.runCmd(packageID, peerConnection)
+	if err != nil {
+		return nil, err
+	}
+
+	if b.Sandbox != nil {
+		return b.Sandbox.Wrap(runCmd, b.LogFormatJSON, b.LogSink)
+	}
+	return StartCaptured(b.Logger, runCmd, b.LogFormatJSON, b.LogSink)
+}
+
+func (b *Builder) runCmd(packageID string, peerConnection *ccintf.PeerConnection) (*exec.Cmd, error) {
+	runPath := filepath.Join(b.Location, "bin", "run")
+	if _, err := os.Stat(runPath); err != nil {
+		return nil, errors.Wrapf(err, "could not stat run script for builder '%s'", b.Name)
+	}
+
+	cmd := exec.Command(runPath, packageID)
+	cmd.Env = os.Environ()
+	if peerConnection != nil {
+		cmd.Env = append(cmd.Env,
+			fmt.Sprintf("CORE_PEER_ADDRESS=%s", peerConnection.Address),
+		)
+	}
+
+	return cmd, nil
+}
@@ -0,0 +1,203 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package externalbuilder
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/core/container/ccintf"
+)
+
+// RestartPolicy controls how a Group reacts when one of its member
+// Instances exits.
+type RestartPolicy int
+
+const (
+	// RestartNever leaves a member stopped once it exits, and cascades the
+	// stop to its siblings.
+	RestartNever RestartPolicy = iota
+	// RestartAlways restarts a member whenever it exits, regardless of
+	// whether it exited cleanly.
+	RestartAlways
+)
+
+// Member is a named Instance managed by a Group, along with the peer
+// connection it should be (re)started with.
+type Member struct {
+	Name       string
+	Instance   *Instance
+	Connection *ccintf.PeerConnection
+}
+
+// Group supervises a collection of external chaincode Instances the way
+// ifrit's grouper.NewParallel supervises a set of ifrit.Runners: every
+// member is started concurrently, a Ready channel closes once all members
+// have started successfully, and the exit of any one member either
+// restarts it (per RestartPolicy, with exponential backoff) or cascades a
+// stop to every other member.
+//
+// Group is the sole restart authority for its members: Start forces the
+// RestartPolicy of any member's Instance.HealthCheck to RestartKindNever,
+// so a failing probe only stops the instance instead of restarting it
+// behind Group's back. Without this, a HealthCheck-driven restart racing
+// supervise's blocking Wait could make a self-healed instance look like an
+// unplanned exit and trigger a spurious cascade stop of its siblings.
+type Group struct {
+	Members       []Member
+	RestartPolicy RestartPolicy
+	Logger        *flogging.FabricLogger
+
+	// BackoffBase and BackoffMax bound the exponential backoff applied
+	// between restart attempts under RestartAlways. They default to 1s and
+	// 30s respectively when unset.
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+
+	ready    chan struct{}
+	doneCh   chan struct{}
+	stopOnce sync.Once
+}
+
+// Ready returns a channel that is closed once every member has completed
+// Start successfully.
+func (g *Group) Ready() <-chan struct{} {
+	if g.ready == nil {
+		g.ready = make(chan struct{})
+	}
+	return g.ready
+}
+
+// Start launches every member concurrently and blocks until either all of
+// them have started successfully (closing Ready), or one of them fails to
+// start, in which case the members that did start are stopped and the
+// error is returned.
+func (g *Group) Start() error {
+	g.doneCh = make(chan struct{})
+	if g.ready == nil {
+		g.ready = make(chan struct{})
+	}
+
+	for _, member := range g.Members {
+		if member.Instance.HealthCheck != nil {
+			member.Instance.HealthCheck.RestartPolicy = RestartKindNever
+		}
+	}
+
+	errs := make([]error, len(g.Members))
+	var wg sync.WaitGroup
+	wg.Add(len(g.Members))
+	for idx, member := range g.Members {
+		go func(idx int, member Member) {
+			defer wg.Done()
+			errs[idx] = member.Instance.Start(member.Connection)
+		}(idx, member)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			g.stopStarted(errs)
+			return err
+		}
+	}
+
+	close(g.ready)
+	for _, member := range g.Members {
+		go g.supervise(member)
+	}
+
+	return nil
+}
+
+// stopStarted stops every member whose Start did not error, used to unwind
+// a partially-started Group.
+func (g *Group) stopStarted(errs []error) {
+	for idx, member := range g.Members {
+		if errs[idx] == nil {
+			member.Instance.Stop() // nolint:errcheck
+		}
+	}
+}
+
+// supervise watches a single member for exit and either restarts it or
+// cascades a Stop to the rest of the Group, according to RestartPolicy.
+func (g *Group) supervise(member Member) {
+	backoff := g.BackoffBase
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+	backoffMax := g.BackoffMax
+	if backoffMax <= 0 {
+		backoffMax = 30 * time.Second
+	}
+
+	for {
+		_, err := member.Instance.Wait()
+		select {
+		case <-g.doneCh:
+			return
+		default:
+		}
+
+		if g.RestartPolicy != RestartAlways {
+			if g.Logger != nil {
+				g.Logger.Infow("group member exited, stopping siblings", "member", member.Name, "error", err)
+			}
+			g.Stop()
+			return
+		}
+
+		if g.Logger != nil {
+			g.Logger.Infow("group member exited, restarting", "member", member.Name, "error", err, "backoff", backoff)
+		}
+
+		select {
+		case <-g.doneCh:
+			return
+		case <-time.After(backoff):
+		}
+
+		if startErr := member.Instance.Start(member.Connection); startErr != nil {
+			if g.Logger != nil {
+				g.Logger.Errorw("group member failed to restart", "member", member.Name, "error", startErr)
+			}
+			g.Stop()
+			return
+		}
+
+		backoff *= 2
+		if backoff > backoffMax {
+			backoff = backoffMax
+		}
+	}
+}
+
+// Stop terminates every member of the Group. It is safe to call multiple
+// times and from multiple goroutines; only the first call has effect.
+func (g *Group) Stop() {
+	g.stopOnce.Do(func() {
+		if g.doneCh != nil {
+			close(g.doneCh)
+		}
+		for _, member := range g.Members {
+			member.Instance.Stop() // nolint:errcheck
+		}
+	})
+}
+
+// Wait blocks until the Group has been stopped, i.e. until Stop is called
+// because a member exited under RestartNever, a restart failed under
+// RestartAlways, or a caller called Stop directly. It does not block on any
+// individual member's Session: under RestartAlways, supervise swaps in a
+// new Session each time a member is restarted, so waiting on a
+// once-snapshotted Session would return as soon as that member's original
+// process exited, even though supervise had already relaunched it.
+func (g *Group) Wait() {
+	<-g.doneCh
+}
@@ -0,0 +1,189 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package externalbuilder_test
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/core/container/ccintf"
+	"github.com/hyperledger/fabric/core/container/externalbuilder"
+)
+
+var _ = Describe("Group", func() {
+	var (
+		logger  *flogging.FabricLogger
+		group   *externalbuilder.Group
+		members []externalbuilder.Member
+	)
+
+	BeforeEach(func() {
+		enc := zapcore.NewConsoleEncoder(zapcore.EncoderConfig{MessageKey: "msg"})
+		core := zapcore.NewCore(enc, zapcore.AddSync(GinkgoWriter), zap.NewAtomicLevel())
+		logger = flogging.NewFabricLogger(zap.New(core).Named("logger"))
+
+		newMember := func(name, location string) externalbuilder.Member {
+			return externalbuilder.Member{
+				Name: name,
+				Instance: &externalbuilder.Instance{
+					PackageID: name,
+					Builder: &externalbuilder.Builder{
+						Location: location,
+						Name:     name,
+						Logger:   logger,
+					},
+					TermTimeout: 5 * time.Second,
+				},
+				Connection: &ccintf.PeerConnection{Address: "fake-peer-address"},
+			}
+		}
+
+		members = []externalbuilder.Member{
+			newMember("cc-one", "testdata/longrunner"),
+			newMember("cc-two", "testdata/longrunner"),
+		}
+		group = &externalbuilder.Group{
+			Members: members,
+			Logger:  logger,
+		}
+	})
+
+	Describe("Start", func() {
+		It("starts every member and closes Ready once they've all started", func() {
+			err := group.Start()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(group.Ready()).To(BeClosed())
+
+			for _, member := range members {
+				Expect(member.Instance.Session).NotTo(BeNil())
+			}
+		})
+	})
+
+	Describe("cascade stop", func() {
+		BeforeEach(func() {
+			members[0].Instance.Builder.Location = "testdata/failbuilder"
+			members[0].Instance.Builder.Name = "failbuilder"
+		})
+
+		It("stops every sibling once one member exits under RestartNever", func() {
+			err := group.Start()
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(func() error {
+				_, err := members[1].Instance.Wait()
+				return err
+			}, 5*time.Second).Should(MatchError(ContainSubstring("signal: terminated")))
+		})
+	})
+
+	Describe("a member with a HealthCheck", func() {
+		var probe *fakeProbe
+
+		BeforeEach(func() {
+			probe = &fakeProbe{}
+			members[0].Instance.HealthCheck = &externalbuilder.HealthCheck{
+				Probe:            probe,
+				Interval:         20 * time.Millisecond,
+				FailureThreshold: 2,
+				RestartPolicy:    externalbuilder.RestartKindAlways,
+				Logger:           logger,
+			}
+		})
+
+		It("forces the member's HealthCheck to stop rather than restart it, leaving the restart decision to Group", func() {
+			err := group.Start()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(members[0].Instance.HealthCheck.RestartPolicy).To(Equal(externalbuilder.RestartKindNever))
+
+			probe.setFailing(true)
+
+			// Under the Group's default RestartNever policy, the unhealthy
+			// member being stopped should cascade a stop to its sibling,
+			// rather than the member quietly restarting itself and leaving
+			// the sibling's fate undecided.
+			Eventually(func() error {
+				_, err := members[1].Instance.Wait()
+				return err
+			}, 5*time.Second).Should(MatchError(ContainSubstring("signal: terminated")))
+		})
+	})
+
+	Describe("Wait", func() {
+		BeforeEach(func() {
+			members[0].Instance.Builder.Location = "testdata/goodbuilder"
+			members[0].Instance.Builder.Name = "goodbuilder"
+			group.RestartPolicy = externalbuilder.RestartAlways
+			group.BackoffBase = 10 * time.Millisecond
+			group.BackoffMax = 10 * time.Millisecond
+		})
+
+		It("does not return while a restarted member is still being relaunched, only once Stop is called", func() {
+			err := group.Start()
+			Expect(err).NotTo(HaveOccurred())
+
+			doneCh := make(chan struct{})
+			go func() {
+				defer close(doneCh)
+				group.Wait()
+			}()
+
+			// members[0] exits and is restarted under RestartAlways
+			// repeatedly; Group.Wait must not mistake any one of those
+			// exits for the whole Group being done.
+			Consistently(doneCh, 200*time.Millisecond).ShouldNot(BeClosed())
+
+			group.Stop()
+			Eventually(doneCh, 5*time.Second).Should(BeClosed())
+		})
+	})
+
+	Describe("per-member TermTimeout", func() {
+		BeforeEach(func() {
+			members[0].Instance.Builder.Location = "testdata/stubborn"
+			members[0].Instance.Builder.Name = "stubborn"
+			members[0].Instance.TermTimeout = 500 * time.Millisecond
+			members[1].Instance.TermTimeout = time.Minute
+		})
+
+		It("escalates only the stubborn member to SIGKILL while its sibling exits cleanly", func() {
+			readyDir, err := os.MkdirTemp("", "stubborn-ready")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(readyDir)
+
+			readyFile := filepath.Join(readyDir, "trap-installed")
+			os.Setenv("STUBBORN_READY_FILE", readyFile)
+			defer os.Unsetenv("STUBBORN_READY_FILE")
+
+			err = group.Start()
+			Expect(err).NotTo(HaveOccurred())
+
+			// Wait for the stubborn member's script to signal that it has
+			// installed its SIGTERM trap, so Stop deterministically
+			// exercises the escalation to SIGKILL rather than racing
+			// process startup.
+			Eventually(func() error {
+				_, err := os.Stat(readyFile)
+				return err
+			}, time.Second, 5*time.Millisecond).Should(Succeed())
+			group.Stop()
+
+			_, err0 := members[0].Instance.Wait()
+			Expect(err0).To(MatchError(ContainSubstring("signal: killed")))
+
+			_, err1 := members[1].Instance.Wait()
+			Expect(err1).To(MatchError(ContainSubstring("signal: terminated")))
+		})
+	})
+})
@@ -0,0 +1,175 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package externalbuilder
+
+import (
+	"net"
+	"os/exec"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+)
+
+// Probe reports whether a running chaincode instance is healthy. An error
+// return counts as a failed probe.
+type Probe interface {
+	Check() error
+}
+
+// TCPProbe dials Address and considers the instance healthy if the
+// connection succeeds within Timeout.
+type TCPProbe struct {
+	Address string
+	Timeout time.Duration
+}
+
+// Check implements Probe by dialing Address.
+func (p *TCPProbe) Check() error {
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+
+	conn, err := net.DialTimeout("tcp", p.Address, timeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// ExecProbe considers the instance healthy if running Command exits zero.
+type ExecProbe struct {
+	Command []string
+}
+
+// Check implements Probe by running the configured command.
+func (p *ExecProbe) Check() error {
+	if len(p.Command) == 0 {
+		return nil
+	}
+	return exec.Command(p.Command[0], p.Command[1:]...).Run()
+}
+
+// ScriptProbe runs the builder-provided "healthcheck" script in Location
+// and considers the instance healthy if it exits zero.
+type ScriptProbe struct {
+	Location  string
+	PackageID string
+}
+
+// Check implements Probe by running <Location>/bin/healthcheck.
+func (p *ScriptProbe) Check() error {
+	return exec.Command(p.Location+"/bin/healthcheck", p.PackageID).Run()
+}
+
+// RestartPolicyKind controls whether and how HealthCheck restarts an
+// instance whose probe has failed FailureThreshold consecutive times.
+// HealthCheck does not watch the process for exit, so under every kind an
+// instance whose process exits on its own is not restarted.
+type RestartPolicyKind int
+
+const (
+	// RestartKindNever leaves the instance stopped once its probe has
+	// failed FailureThreshold times.
+	RestartKindNever RestartPolicyKind = iota
+	// RestartKindOnFailure restarts the instance after probe failures,
+	// with exponential backoff up to MaxRetries attempts (0 means
+	// unlimited).
+	RestartKindOnFailure
+	// RestartKindAlways behaves exactly like RestartKindOnFailure:
+	// restarts follow probe failures only, with the same backoff and
+	// MaxRetries.
+	RestartKindAlways
+)
+
+// HealthCheck evaluates Probe on Interval once an Instance has started, and
+// stops/restarts it according to RestartPolicy after FailureThreshold
+// consecutive failures.
+type HealthCheck struct {
+	Probe            Probe
+	Interval         time.Duration
+	FailureThreshold int
+	RestartPolicy    RestartPolicyKind
+	BackoffBase      time.Duration
+	BackoffMax       time.Duration
+	MaxRetries       int
+
+	Logger *flogging.FabricLogger
+}
+
+// Status reports the current health and restart bookkeeping of an Instance
+// for operators to surface through metrics.
+type Status struct {
+	Running           bool
+	RestartCount      int
+	ConsecutiveFails  int
+	LastFailureReason string
+}
+
+func (h *HealthCheck) interval() time.Duration {
+	if h.Interval <= 0 {
+		return 10 * time.Second
+	}
+	return h.Interval
+}
+
+func (h *HealthCheck) threshold() int {
+	if h.FailureThreshold <= 0 {
+		return 3
+	}
+	return h.FailureThreshold
+}
+
+// backoffFor returns the delay to wait before the attempt-th restart,
+// doubling from BackoffBase (default 1s) up to BackoffMax (default 30s).
+func (h *HealthCheck) backoffFor(attempt int) time.Duration {
+	base := h.BackoffBase
+	if base <= 0 {
+		base = time.Second
+	}
+	max := h.BackoffMax
+	if max <= 0 {
+		max = 30 * time.Second
+	}
+
+	backoff := base
+	for n := 1; n < attempt; n++ {
+		backoff *= 2
+		if backoff >= max {
+			return max
+		}
+	}
+	return backoff
+}
+
+// run polls Probe on Interval and invokes onUnhealthy once FailureThreshold
+// consecutive failures have been observed. It returns when stopCh closes.
+func (h *HealthCheck) run(stopCh <-chan struct{}, onUnhealthy func(reason string)) {
+	ticker := time.NewTicker(h.interval())
+	defer ticker.Stop()
+
+	fails := 0
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			if err := h.Probe.Check(); err != nil {
+				fails++
+				if h.Logger != nil {
+					h.Logger.Warnw("health probe failed", "consecutiveFailures", fails, "error", err)
+				}
+				if fails >= h.threshold() {
+					onUnhealthy(err.Error())
+					return
+				}
+				continue
+			}
+			fails = 0
+		}
+	}
+}
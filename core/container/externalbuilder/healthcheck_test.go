@@ -0,0 +1,132 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package externalbuilder_test
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/core/container/ccintf"
+	"github.com/hyperledger/fabric/core/container/externalbuilder"
+)
+
+type fakeProbe struct {
+	checks int32
+	fail   int32 // atomically toggled: non-zero means Check fails
+}
+
+func (p *fakeProbe) Check() error {
+	atomic.AddInt32(&p.checks, 1)
+	if atomic.LoadInt32(&p.fail) != 0 {
+		return errors.New("probe unreachable")
+	}
+	return nil
+}
+
+func (p *fakeProbe) setFailing(failing bool) {
+	v := int32(0)
+	if failing {
+		v = 1
+	}
+	atomic.StoreInt32(&p.fail, v)
+}
+
+var _ = Describe("HealthCheck", func() {
+	var (
+		logger   *flogging.FabricLogger
+		instance *externalbuilder.Instance
+		probe    *fakeProbe
+	)
+
+	BeforeEach(func() {
+		enc := zapcore.NewConsoleEncoder(zapcore.EncoderConfig{MessageKey: "msg"})
+		core := zapcore.NewCore(enc, zapcore.AddSync(GinkgoWriter), zap.NewAtomicLevel())
+		logger = flogging.NewFabricLogger(zap.New(core).Named("logger"))
+
+		probe = &fakeProbe{}
+		instance = &externalbuilder.Instance{
+			PackageID: "test-ccid",
+			Builder: &externalbuilder.Builder{
+				Location: "testdata/longrunner",
+				Logger:   logger,
+			},
+			TermTimeout: 5 * time.Second,
+			HealthCheck: &externalbuilder.HealthCheck{
+				Probe:            probe,
+				Interval:         20 * time.Millisecond,
+				FailureThreshold: 2,
+				RestartPolicy:    externalbuilder.RestartKindOnFailure,
+				BackoffBase:      10 * time.Millisecond,
+				Logger:           logger,
+			},
+		}
+	})
+
+	AfterEach(func() {
+		instance.Stop() // nolint:errcheck
+	})
+
+	Context("when the probe fails consecutively past the threshold", func() {
+		It("restarts the instance and increments the restart count", func() {
+			Expect(instance.Start(&ccintf.PeerConnection{Address: "fake-peer-address"})).To(Succeed())
+			firstSession := instance.Session
+
+			probe.setFailing(true)
+
+			Eventually(func() int {
+				return instance.Status().RestartCount
+			}, 2*time.Second, 10*time.Millisecond).Should(BeNumerically(">=", 1))
+
+			Eventually(func() *externalbuilder.Session {
+				return instance.Session
+			}, 2*time.Second, 10*time.Millisecond).ShouldNot(BeIdenticalTo(firstSession))
+
+			Expect(instance.Status().LastFailureReason).To(ContainSubstring("probe unreachable"))
+		})
+	})
+
+	Context("when the probe recovers before the failure threshold is hit", func() {
+		It("does not restart the instance", func() {
+			Expect(instance.Start(&ccintf.PeerConnection{Address: "fake-peer-address"})).To(Succeed())
+			firstSession := instance.Session
+
+			probe.setFailing(true)
+			time.Sleep(25 * time.Millisecond)
+			probe.setFailing(false)
+
+			Consistently(func() int {
+				return instance.Status().RestartCount
+			}, 200*time.Millisecond, 20*time.Millisecond).Should(Equal(0))
+			Expect(instance.Session).To(BeIdenticalTo(firstSession))
+		})
+	})
+
+	Context("when Stop is called while a probe failure is in flight", func() {
+		It("does not let the health check trigger a restart after shutdown has begun", func() {
+			Expect(instance.Start(&ccintf.PeerConnection{Address: "fake-peer-address"})).To(Succeed())
+			probe.setFailing(true)
+
+			// Give the probe loop a chance to observe at least one failure
+			// before shutdown begins, to exercise the race with Stop.
+			time.Sleep(25 * time.Millisecond)
+
+			err := instance.Stop()
+			Expect(err).NotTo(HaveOccurred())
+
+			Consistently(func() int {
+				return instance.Status().RestartCount
+			}, 200*time.Millisecond, 20*time.Millisecond).Should(Equal(0))
+		})
+	})
+})
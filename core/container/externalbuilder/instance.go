@@ -0,0 +1,195 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package externalbuilder
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/core/container/ccintf"
+	"github.com/pkg/errors"
+)
+
+// Instance represents a chaincode process that was launched by an external
+// builder's run script.
+type Instance struct {
+	PackageID   string
+	Builder     *Builder
+	Session     *Session
+	TermTimeout time.Duration
+
+	// HealthCheck is optional. When set, Start arms a probe loop against
+	// the running process and, on FailureThreshold consecutive failures,
+	// stops and/or restarts the instance according to its RestartPolicy.
+	HealthCheck *HealthCheck
+
+	mutex      sync.Mutex
+	connection *ccintf.PeerConnection
+	stopHealth chan struct{}
+	stopping   bool
+	status     Status
+}
+
+// Start launches the chaincode's run script and connects it to the peer
+// described by peerConnection. It returns once the process has been
+// launched; it does not wait for the process to exit. If HealthCheck is
+// set, this also (re)arms its probe loop.
+func (i *Instance) Start(peerConnection *ccintf.PeerConnection) error {
+	sess, err := i.Builder.Run(i.PackageID, peerConnection)
+	if err != nil {
+		return errors.WithMessage(err, fmt.Sprintf("builder '%s' run failed", i.Builder.Name))
+	}
+
+	i.mutex.Lock()
+	i.Session = sess
+	i.connection = peerConnection
+	i.stopping = false
+	i.status.Running = true
+	var stopHealth chan struct{}
+	if i.HealthCheck != nil {
+		stopHealth = make(chan struct{})
+		i.stopHealth = stopHealth
+	}
+	i.mutex.Unlock()
+
+	if stopHealth != nil {
+		go i.monitorHealth(stopHealth)
+	}
+
+	return nil
+}
+
+// Stop terminates the chaincode process, escalating from SIGTERM to SIGKILL
+// if it does not exit within TermTimeout. Any armed HealthCheck probe loop
+// is disarmed first so a probe failure racing with a graceful shutdown
+// can't trigger a spurious restart.
+func (i *Instance) Stop() error {
+	i.mutex.Lock()
+	i.stopping = true
+	i.status.Running = false
+	sess := i.Session
+	stopHealth := i.stopHealth
+	i.stopHealth = nil
+	i.mutex.Unlock()
+
+	if sess == nil {
+		return errors.New("instance has not been started")
+	}
+
+	if stopHealth != nil {
+		close(stopHealth)
+	}
+
+	sess.TerminateThenKill(i.TermTimeout)
+	return nil
+}
+
+// Wait blocks until the chaincode process exits and returns its exit code.
+// If the process exited with a non-zero status, the returned error wraps
+// the OS-level error with the builder's name for easier diagnosis.
+func (i *Instance) Wait() (int, error) {
+	i.mutex.Lock()
+	sess := i.Session
+	i.mutex.Unlock()
+
+	if sess == nil {
+		return -1, errors.New("instance was not successfully started")
+	}
+
+	waitErr := sess.Wait()
+	exitCode := sess.ExitCode()
+	if waitErr != nil {
+		msg := fmt.Sprintf("builder '%s' run failed", i.Builder.Name)
+		if tail := i.errorTail(sess); tail != "" {
+			msg += fmt.Sprintf(" (last output: %s)", tail)
+		}
+		return exitCode, errors.WithMessage(waitErr, msg)
+	}
+
+	return exitCode, nil
+}
+
+// Status reports the instance's current health-check bookkeeping so
+// operators can wire it into peer metrics.
+func (i *Instance) Status() Status {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	return i.status
+}
+
+// errorTail joins the most recent lines captured from sess's stderr, if
+// any were captured, for inclusion in a failed Wait's error so operators
+// aren't left with only the OS-level exit status.
+func (i *Instance) errorTail(sess *Session) string {
+	if sess.Stderr == nil {
+		return ""
+	}
+
+	lines := sess.Stderr.Tail()
+	if len(lines) == 0 {
+		return ""
+	}
+
+	return strings.Join(lines, "; ")
+}
+
+// monitorHealth runs HealthCheck's probe loop and reacts to a sustained
+// failure according to its RestartPolicy. It returns once stopCh is closed
+// by Stop, or once it has handed off an unhealthy instance to Stop/restart.
+func (i *Instance) monitorHealth(stopCh chan struct{}) {
+	i.HealthCheck.run(stopCh, func(reason string) {
+		i.mutex.Lock()
+		i.status.ConsecutiveFails = i.HealthCheck.threshold()
+		i.status.LastFailureReason = reason
+		stopping := i.stopping
+		i.mutex.Unlock()
+
+		if stopping {
+			return
+		}
+
+		if i.HealthCheck.RestartPolicy == RestartKindNever {
+			i.Stop() // nolint:errcheck
+			return
+		}
+
+		i.restart(reason)
+	})
+}
+
+// restart terminates the current process and relaunches the instance with
+// the same peer connection, applying HealthCheck's backoff and MaxRetries.
+func (i *Instance) restart(reason string) {
+	i.mutex.Lock()
+	sess := i.Session
+	conn := i.connection
+	i.mutex.Unlock()
+
+	if sess != nil {
+		sess.TerminateThenKill(i.TermTimeout)
+	}
+
+	i.mutex.Lock()
+	i.status.RestartCount++
+	attempt := i.status.RestartCount
+	i.mutex.Unlock()
+
+	if i.HealthCheck.MaxRetries > 0 && attempt > i.HealthCheck.MaxRetries {
+		if i.HealthCheck.Logger != nil {
+			i.HealthCheck.Logger.Errorw("instance exceeded max health-check restarts, giving up", "packageID", i.PackageID, "restarts", attempt, "reason", reason)
+		}
+		return
+	}
+
+	time.Sleep(i.HealthCheck.backoffFor(attempt))
+
+	if err := i.Start(conn); err != nil && i.HealthCheck.Logger != nil {
+		i.HealthCheck.Logger.Errorw("instance failed to restart after health-check failure", "packageID", i.PackageID, "error", err)
+	}
+}
@@ -7,7 +7,9 @@ SPDX-License-Identifier: Apache-2.0
 package externalbuilder_test
 
 import (
+	"os"
 	"os/exec"
+	"runtime"
 	"time"
 
 	. "github.com/onsi/ginkgo"
@@ -95,6 +97,38 @@ var _ = Describe("Instance", func() {
 			})
 		})
 
+		Context("when the builder is sandboxed with a memory limit", func() {
+			It("is terminated by the cgroup limiter instead of relying on TermTimeout", func() {
+				if runtime.GOOS != "linux" {
+					Skip("cgroups v2 sandboxing is only enforced on linux")
+				}
+				if _, err := os.Stat("/sys/fs/cgroup/cgroup.controllers"); err != nil {
+					Skip("host is not running a cgroups v2 unified hierarchy")
+				}
+
+				cmd := exec.Command("testdata/memoryhog.sh")
+				sandbox := &externalbuilder.Sandbox{
+					Logger: logger,
+					Config: externalbuilder.SandboxConfig{
+						MemoryLimitBytes: 8 * 1024 * 1024,
+					},
+				}
+				sess, err := sandbox.Wrap(cmd, false, nil)
+				Expect(err).NotTo(HaveOccurred())
+
+				instance.Session = sess
+				// TermTimeout is intentionally generous here: the cgroup memory
+				// limiter, not the SIGTERM/SIGKILL escalation above, is expected
+				// to be what ends the runaway process well before its own
+				// 5 second sleep would otherwise let it exit cleanly.
+				instance.TermTimeout = time.Minute
+
+				errCh := make(chan error)
+				go func() { errCh <- instance.Session.Wait() }()
+				Eventually(errCh, 4*time.Second).Should(Receive(MatchError("signal: killed")))
+			})
+		})
+
 		Context("when the instance session has not been started", func() {
 			It("returns an error", func() {
 				instance.Session = nil
@@ -135,7 +169,7 @@ var _ = Describe("Instance", func() {
 
 			It("returns the exit status of the run and accompanying error", func() {
 				code, err := instance.Wait()
-				Expect(err).To(MatchError("builder 'failbuilder' run failed: exit status 1"))
+				Expect(err).To(MatchError(`builder 'failbuilder' run failed (last output: {"level":"error","msg":"failed to bind chaincode listener","phase":"run"}): exit status 1`))
 				Expect(code).To(Equal(1))
 			})
 		})
@@ -0,0 +1,105 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package externalbuilder
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"sync"
+
+	"github.com/hyperledger/fabric/common/flogging"
+)
+
+// maxTailLines bounds how many recent lines LineWriter retains for Instance
+// to surface when a builder exits with a non-zero status.
+const maxTailLines = 20
+
+// maxLineBytes caps the size of a single captured line so a builder that
+// writes unbounded output to stdout/stderr without newlines can't grow
+// LineWriter's buffers without limit.
+const maxLineBytes = 64 * 1024
+
+// LineWriter reads newline-delimited output from a builder subprocess,
+// parses each line as JSON when JSONLogs is set (per the build-config
+// "log-format: json" opt-in), logs it through Logger with structured
+// fields, and forwards it to Sink if one is configured. It also retains the
+// most recent lines so Instance.Wait can include them in its error when the
+// process exits non-zero.
+type LineWriter struct {
+	Logger   *flogging.FabricLogger
+	Sink     LogSink
+	JSONLogs bool
+
+	mutex sync.Mutex
+	tail  []string
+}
+
+// Forward copies lines from r until EOF, processing each as described on
+// LineWriter. A line longer than maxLineBytes ends line processing: the
+// rest of r is read and discarded, and bufio.ErrTooLong is returned. It
+// returns any error encountered reading from r; a full or misbehaving Sink
+// does not abort the copy, but is logged.
+func (w *LineWriter) Forward(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 4096), maxLineBytes)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		record := w.parse(line)
+		w.remember(line)
+
+		if w.Logger != nil {
+			w.Logger.Infow("builder output", "msg", record.Msg, "phase", record.Phase, "level", record.Level)
+		}
+
+		if w.Sink != nil {
+			if err := w.Sink.Accept(record); err != nil && w.Logger != nil {
+				w.Logger.Warnw("builder log sink rejected a line", "error", err)
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		io.Copy(io.Discard, r) // nolint:errcheck
+		return err
+	}
+	return nil
+}
+
+func (w *LineWriter) parse(line string) LogRecord {
+	if !w.JSONLogs {
+		return LogRecord{Msg: line, Raw: line}
+	}
+
+	var record LogRecord
+	if err := json.Unmarshal([]byte(line), &record); err != nil {
+		return LogRecord{Msg: line, Raw: line}
+	}
+	record.Raw = line
+	return record
+}
+
+func (w *LineWriter) remember(line string) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.tail = append(w.tail, line)
+	if len(w.tail) > maxTailLines {
+		w.tail = w.tail[len(w.tail)-maxTailLines:]
+	}
+}
+
+// Tail returns the most recently captured lines, oldest first.
+func (w *LineWriter) Tail() []string {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	tail := make([]string, len(w.tail))
+	copy(tail, w.tail)
+	return tail
+}
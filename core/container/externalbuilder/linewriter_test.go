@@ -0,0 +1,78 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package externalbuilder_test
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/core/container/externalbuilder"
+)
+
+type recordingSink struct {
+	records []externalbuilder.LogRecord
+}
+
+func (s *recordingSink) Accept(record externalbuilder.LogRecord) error {
+	s.records = append(s.records, record)
+	return nil
+}
+
+var _ = Describe("LineWriter", func() {
+	var (
+		logger *flogging.FabricLogger
+		sink   *recordingSink
+		writer *externalbuilder.LineWriter
+	)
+
+	BeforeEach(func() {
+		enc := zapcore.NewConsoleEncoder(zapcore.EncoderConfig{MessageKey: "msg"})
+		core := zapcore.NewCore(enc, zapcore.AddSync(GinkgoWriter), zap.NewAtomicLevel())
+		logger = flogging.NewFabricLogger(zap.New(core).Named("logger"))
+
+		sink = &recordingSink{}
+		writer = &externalbuilder.LineWriter{Logger: logger, Sink: sink, JSONLogs: true}
+	})
+
+	Describe("Forward", func() {
+		It("parses each line as JSON and forwards it to the sink", func() {
+			input := strings.NewReader(`{"level":"info","msg":"starting","phase":"build"}` + "\n")
+
+			err := writer.Forward(input)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sink.records).To(HaveLen(1))
+			Expect(sink.records[0].Level).To(Equal("info"))
+			Expect(sink.records[0].Phase).To(Equal("build"))
+		})
+
+		Context("when a line isn't valid JSON", func() {
+			It("falls back to treating it as a raw message", func() {
+				input := strings.NewReader("plain text output\n")
+
+				err := writer.Forward(input)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(sink.records).To(HaveLen(1))
+				Expect(sink.records[0].Msg).To(Equal("plain text output"))
+			})
+		})
+	})
+
+	Describe("Tail", func() {
+		It("retains only the most recent lines", func() {
+			for i := 0; i < 25; i++ {
+				writer.Forward(strings.NewReader("line\n"))
+			}
+
+			Expect(writer.Tail()).To(HaveLen(20))
+		})
+	})
+})
@@ -0,0 +1,118 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package externalbuilder
+
+import (
+	"encoding/json"
+	"log/syslog"
+	"os"
+)
+
+// LogSink receives structured log lines captured from a builder session's
+// stdout/stderr. Implementations are expected to be safe for concurrent use
+// since a session may forward from both streams at once.
+type LogSink interface {
+	// Accept is called once per parsed log line. Implementations should not
+	// block for long; Accept is called inline with the builder's output
+	// pipe being drained, and a slow sink applies backpressure to the
+	// builder itself.
+	Accept(LogRecord) error
+}
+
+// LogRecord is a single structured log line emitted by a builder that opted
+// in to "log-format: json" in its build-config.
+type LogRecord struct {
+	Level string `json:"level"`
+	Time  string `json:"ts"`
+	Msg   string `json:"msg"`
+	Phase string `json:"phase"`
+
+	// Raw holds the original line exactly as the builder wrote it, whether
+	// or not it parsed as the LogRecord shape above. It is left out of the
+	// record's JSON encoding, so FileLogSink and SyslogLogSink do not write
+	// it.
+	Raw string `json:"-"`
+}
+
+// FileLogSink appends each record to a file as a single JSON line.
+type FileLogSink struct {
+	path string
+
+	mu   chan struct{} // 1-buffered mutex so Accept is safe for concurrent use
+	file *os.File
+}
+
+// NewFileLogSink opens (creating if necessary) the file at path for
+// appending structured log records.
+func NewFileLogSink(path string) (*FileLogSink, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	mu := make(chan struct{}, 1)
+	mu <- struct{}{}
+	return &FileLogSink{path: path, file: f, mu: mu}, nil
+}
+
+// Accept appends record to the sink's file as a single JSON line.
+func (s *FileLogSink) Accept(record LogRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	<-s.mu
+	defer func() { s.mu <- struct{}{} }()
+
+	_, err = s.file.Write(append(line, '\n'))
+	return err
+}
+
+// Close releases the sink's underlying file handle.
+func (s *FileLogSink) Close() error {
+	return s.file.Close()
+}
+
+// SyslogLogSink forwards records to a syslog writer, using Level to pick the
+// priority and falling back to Info when Level is unset or unrecognized.
+type SyslogLogSink struct {
+	writer *syslog.Writer
+}
+
+// NewSyslogLogSink dials the local syslog daemon, tagging entries with tag.
+func NewSyslogLogSink(tag string) (*SyslogLogSink, error) {
+	w, err := syslog.New(syslog.LOG_INFO, tag)
+	if err != nil {
+		return nil, err
+	}
+	return &SyslogLogSink{writer: w}, nil
+}
+
+// Accept writes record to syslog at the priority implied by its Level.
+func (s *SyslogLogSink) Accept(record LogRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	switch record.Level {
+	case "error", "fatal", "panic":
+		return s.writer.Err(string(line))
+	case "warn", "warning":
+		return s.writer.Warning(string(line))
+	case "debug":
+		return s.writer.Debug(string(line))
+	default:
+		return s.writer.Info(string(line))
+	}
+}
+
+// Close releases the sink's underlying syslog connection.
+func (s *SyslogLogSink) Close() error {
+	return s.writer.Close()
+}
@@ -0,0 +1,196 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package externalbuilder
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// Manifest describes a single builder offering published by a registry, as
+// fetched from the registry's HTTP(S) endpoint.
+type Manifest struct {
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	Type      string `json:"type"` // chaincode language/runtime, e.g. "node", "rust", "wasm"
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+	URL       string `json:"url"`      // location of the packaged builder archive
+	Checksum  string `json:"checksum"` // hex-encoded sha256 of the archive
+	Signature string `json:"signature,omitempty"`
+	SignedBy  string `json:"signedBy,omitempty"`
+}
+
+// Resolved describes a builder that has been fetched and verified, ready to
+// be used as a Builder.Location.
+type Resolved struct {
+	Path    string
+	Version string
+}
+
+// Registry resolves named builders against a remote marketplace: an
+// HTTP(S) endpoint that answers a plain GET with a JSON array of Manifest
+// entries. The OCI distribution API is not spoken, so an OCI registry can
+// only be used if it serves that JSON array at Endpoint. Resolved
+// builders are cached on disk under CacheDir so repeated resolutions don't
+// re-fetch unchanged archives.
+//
+// AllowUnsigned mirrors the externalBuilders[].allowUnsignedRegistry peer
+// config flag; unless it is true, Resolve refuses any manifest entry that
+// doesn't carry a Signature from a trusted publisher.
+type Registry struct {
+	Endpoint      string
+	CacheDir      string
+	AllowUnsigned bool
+	// TrustedKeys maps a signer name (Manifest.SignedBy) to its raw
+	// ed25519 public key. Manifest.Signature is the hex-encoded ed25519
+	// signature over the archive's hex-encoded checksum string.
+	TrustedKeys map[string]ed25519.PublicKey
+
+	httpClient *http.Client
+}
+
+// Resolve looks up name (optionally filtered by ccType) against the
+// registry's manifest list, fetches the archive into CacheDir if it isn't
+// already cached, verifies its checksum and signature, and returns the
+// local path it was extracted to.
+func (r *Registry) Resolve(name, ccType string) (*Resolved, error) {
+	manifest, err := r.lookup(name, ccType)
+	if err != nil {
+		return nil, err
+	}
+
+	if manifest.Signature == "" && !r.AllowUnsigned {
+		return nil, errors.Errorf("builder '%s' version %s is unsigned and allowUnsignedRegistry is not set", name, manifest.Version)
+	}
+
+	dest := filepath.Join(r.CacheDir, name, manifest.Version)
+	if _, err := os.Stat(filepath.Join(dest, "bin", "run")); err == nil {
+		return &Resolved{Path: dest, Version: manifest.Version}, nil
+	}
+
+	if err := r.fetchAndVerify(manifest, dest); err != nil {
+		return nil, err
+	}
+
+	return &Resolved{Path: dest, Version: manifest.Version}, nil
+}
+
+func (r *Registry) lookup(name, ccType string) (*Manifest, error) {
+	client := r.httpClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Get(r.Endpoint)
+	if err != nil {
+		return nil, errors.WithMessage(err, "could not reach external builder registry")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("external builder registry returned status %d", resp.StatusCode)
+	}
+
+	var manifests []Manifest
+	if err := json.NewDecoder(resp.Body).Decode(&manifests); err != nil {
+		return nil, errors.WithMessage(err, "could not parse external builder registry manifest")
+	}
+
+	for _, m := range manifests {
+		if m.Name != name {
+			continue
+		}
+		if ccType != "" && m.Type != ccType {
+			continue
+		}
+		manifest := m
+		return &manifest, nil
+	}
+
+	return nil, errors.Errorf("no builder named '%s' found in registry", name)
+}
+
+func (r *Registry) fetchAndVerify(manifest *Manifest, dest string) error {
+	client := r.httpClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Get(manifest.URL)
+	if err != nil {
+		return errors.WithMessage(err, fmt.Sprintf("could not fetch builder '%s'", manifest.Name))
+	}
+	defer resp.Body.Close()
+
+	hasher := sha256.New()
+	if err := os.MkdirAll(dest, 0o755); err != nil {
+		return errors.WithMessage(err, "could not create builder cache directory")
+	}
+
+	archivePath := filepath.Join(dest, "archive")
+	archive, err := os.Create(archivePath)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	if _, err := io.Copy(io.MultiWriter(archive, hasher), resp.Body); err != nil {
+		return errors.WithMessage(err, "could not download builder archive")
+	}
+
+	sum := hex.EncodeToString(hasher.Sum(nil))
+	if sum != manifest.Checksum {
+		return errors.Errorf("checksum mismatch for builder '%s': expected %s, got %s", manifest.Name, manifest.Checksum, sum)
+	}
+
+	if manifest.Signature != "" {
+		if err := r.verifySignature(manifest, sum); err != nil {
+			return errors.WithMessage(err, fmt.Sprintf("signature verification failed for builder '%s'", manifest.Name))
+		}
+	}
+
+	return extractArchive(archivePath, dest)
+}
+
+// verifySignature checks that manifest.Signature is a valid ed25519
+// signature, by the key registered for manifest.SignedBy, over checksum.
+// It returns an error for any of: an unknown signer, a malformed key or
+// signature, or a signature that doesn't verify - any of which makes
+// Resolve refuse the builder, even when AllowUnsigned is set.
+func (r *Registry) verifySignature(manifest *Manifest, checksum string) error {
+	key, ok := r.TrustedKeys[manifest.SignedBy]
+	if !ok {
+		return errors.Errorf("no trusted key registered for signer '%s'", manifest.SignedBy)
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return errors.Errorf("trusted key for signer '%s' is not a valid ed25519 public key", manifest.SignedBy)
+	}
+
+	sig, err := hex.DecodeString(manifest.Signature)
+	if err != nil {
+		return errors.WithMessage(err, "signature is not valid hex")
+	}
+	if len(sig) != ed25519.SignatureSize {
+		return errors.Errorf("signature has unexpected length %d", len(sig))
+	}
+
+	if !ed25519.Verify(key, []byte(checksum), sig) {
+		return errors.Errorf("signature does not verify against the key registered for signer '%s'", manifest.SignedBy)
+	}
+
+	return nil
+}
@@ -0,0 +1,152 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package externalbuilder_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/hyperledger/fabric/core/container/externalbuilder"
+)
+
+// goodArchive builds a gzip-compressed tarball containing a single,
+// well-behaved bin/run script, suitable for a manifest entry that is
+// expected to resolve successfully.
+func goodArchive() []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	contents := []byte("#!/bin/sh\nexit 0\n")
+	Expect(tw.WriteHeader(&tar.Header{
+		Name:     "bin/run",
+		Typeflag: tar.TypeReg,
+		Mode:     0o755,
+		Size:     int64(len(contents)),
+	})).To(Succeed())
+	_, err := tw.Write(contents)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(tw.Close()).To(Succeed())
+	Expect(gz.Close()).To(Succeed())
+
+	return buf.Bytes()
+}
+
+var _ = Describe("Registry signature verification", func() {
+	var (
+		archive  []byte
+		checksum string
+		pub      ed25519.PublicKey
+		priv     ed25519.PrivateKey
+		cacheDir string
+	)
+
+	BeforeEach(func() {
+		var err error
+		pub, priv, err = ed25519.GenerateKey(nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		archive = goodArchive()
+		sum := sha256.Sum256(archive)
+		checksum = hex.EncodeToString(sum[:])
+
+		// GinkgoT().TempDir() is a no-op in the vendored ginkgo version
+		// this suite runs against, which would make every test in this
+		// Describe share the same on-disk cache and see each other's
+		// already-extracted "node-builder" directory. Use a real unique
+		// temp dir instead.
+		cacheDir, err = os.MkdirTemp("", "registry-signature-test")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(cacheDir)
+	})
+
+	newServer := func(signature string) (*httptest.Server, *externalbuilder.Registry) {
+		mux := http.NewServeMux()
+		var server *httptest.Server
+		mux.HandleFunc("/archive.tar.gz", func(w http.ResponseWriter, r *http.Request) {
+			w.Write(archive)
+		})
+		mux.HandleFunc("/manifest", func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode([]externalbuilder.Manifest{
+				{
+					Name:      "node-builder",
+					Version:   "1.2.3",
+					Type:      "node",
+					URL:       server.URL + "/archive.tar.gz",
+					Checksum:  checksum,
+					Signature: signature,
+					SignedBy:  "trusted-publisher",
+				},
+			})
+		})
+		server = httptest.NewServer(mux)
+
+		registry := &externalbuilder.Registry{
+			Endpoint: server.URL + "/manifest",
+			CacheDir: cacheDir,
+			TrustedKeys: map[string]ed25519.PublicKey{
+				"trusted-publisher": pub,
+			},
+		}
+		return server, registry
+	}
+
+	Context("when the manifest carries a valid ed25519 signature from a trusted signer", func() {
+		It("resolves the builder", func() {
+			sig := ed25519.Sign(priv, []byte(checksum))
+			server, registry := newServer(hex.EncodeToString(sig))
+			defer server.Close()
+
+			resolved, err := registry.Resolve("node-builder", "node")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resolved.Version).To(Equal("1.2.3"))
+		})
+	})
+
+	Context("when the manifest's signature was forged with an untrusted key", func() {
+		It("refuses to resolve the builder", func() {
+			_, forgedPriv, err := ed25519.GenerateKey(nil)
+			Expect(err).NotTo(HaveOccurred())
+			sig := ed25519.Sign(forgedPriv, []byte(checksum))
+
+			server, registry := newServer(hex.EncodeToString(sig))
+			defer server.Close()
+
+			_, err = registry.Resolve("node-builder", "node")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("signature verification failed"))
+		})
+	})
+
+	Context("when the manifest's signature has been tampered with", func() {
+		It("refuses to resolve the builder", func() {
+			sig := ed25519.Sign(priv, []byte(checksum))
+			sig[0] ^= 0xFF
+
+			server, registry := newServer(hex.EncodeToString(sig))
+			defer server.Close()
+
+			_, err := registry.Resolve("node-builder", "node")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("signature verification failed"))
+		})
+	})
+})
@@ -0,0 +1,68 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package externalbuilder_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/hyperledger/fabric/core/container/externalbuilder"
+)
+
+var _ = Describe("Registry", func() {
+	var (
+		server   *httptest.Server
+		registry *externalbuilder.Registry
+		cacheDir string
+	)
+
+	BeforeEach(func() {
+		cacheDir = GinkgoT().TempDir()
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode([]externalbuilder.Manifest{
+				{
+					Name:     "node-builder",
+					Version:  "1.2.3",
+					Type:     "node",
+					URL:      "https://example.invalid/node-builder-1.2.3.tar.gz",
+					Checksum: "deadbeef",
+				},
+			})
+		}))
+
+		registry = &externalbuilder.Registry{
+			Endpoint: server.URL,
+			CacheDir: cacheDir,
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("Resolve", func() {
+		Context("when the manifest entry is unsigned and unsigned registries are not allowed", func() {
+			It("refuses to resolve the builder", func() {
+				_, err := registry.Resolve("node-builder", "node")
+				Expect(err).To(MatchError("builder 'node-builder' version 1.2.3 is unsigned and allowUnsignedRegistry is not set"))
+			})
+		})
+
+		Context("when no builder with the given name is published", func() {
+			It("returns a descriptive error", func() {
+				registry.AllowUnsigned = true
+				_, err := registry.Resolve("missing-builder", "")
+				Expect(err).To(MatchError("no builder named 'missing-builder' found in registry"))
+			})
+		})
+	})
+})
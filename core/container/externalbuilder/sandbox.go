@@ -0,0 +1,148 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package externalbuilder
+
+import (
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/pkg/errors"
+)
+
+// rlimitMutex serializes the read-lower-start-restore sequence in
+// withNoFileLimit across concurrent builder invocations, since RLIMIT_NOFILE
+// is a per-process (not per-thread) limit: without it, two overlapping
+// Wrap calls could restore each other's saved limit instead of their own.
+var rlimitMutex sync.Mutex
+
+// SandboxConfig captures the per-builder resource constraints configured in
+// core.yaml under externalBuilders[].sandbox. Any zero-valued field is left
+// unenforced.
+type SandboxConfig struct {
+	// CPUShares is the relative cgroup cpu.weight allotted to the builder
+	// process, in the same 1-10000 range cgroups v2 uses.
+	CPUShares uint64
+
+	// MemoryLimitBytes caps the builder's resident memory via cgroup
+	// memory.max. The OOM killer terminates the process if it is exceeded.
+	MemoryLimitBytes uint64
+
+	// WallClockTimeout bounds the total time a builder invocation (detect,
+	// build, release or run) is allowed to run before being killed,
+	// independent of TermTimeout on Instance.Stop.
+	WallClockTimeout time.Duration
+
+	// NoFile caps the number of open file descriptors via RLIMIT_NOFILE.
+	NoFile uint64
+}
+
+// Sandbox applies a SandboxConfig to builder invocations. On Linux it is
+// backed by cgroups v2; on other platforms it degrades to enforcing only
+// WallClockTimeout and NoFile, which are available everywhere via
+// context/timer-based process termination and RLIMIT_NOFILE respectively.
+//
+// Sandbox does not restrict syscalls or network egress: SandboxConfig has
+// no field for a seccomp profile or a network allow-list, and builder
+// processes may make any syscall and open any outbound connection their
+// user is permitted to.
+type Sandbox struct {
+	Config SandboxConfig
+	Logger *flogging.FabricLogger
+}
+
+// sandboxApplier is implemented per-platform. Apply is called after the
+// child process has been started (so its pid is known) but before it has
+// had a chance to do meaningful work, and wires the process into the
+// configured cgroup restrictions. Cleanup is called once the process has
+// exited, and undoes whatever state Apply created for that pid.
+type sandboxApplier interface {
+	Apply(pid int, cfg SandboxConfig) error
+	Cleanup(pid int) error
+}
+
+// withNoFileLimit runs fn with the calling process's RLIMIT_NOFILE lowered
+// to nofile for its duration, restoring the previous limit afterward. A
+// child forked while the limit is lowered inherits it, which is the only
+// way to constrain RLIMIT_NOFILE for a process that hasn't been exec'd yet:
+// there is no portable way to set another process's rlimits after the fact.
+func withNoFileLimit(nofile uint64, fn func() error) error {
+	if nofile == 0 {
+		return fn()
+	}
+
+	rlimitMutex.Lock()
+	defer rlimitMutex.Unlock()
+
+	var old syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &old); err != nil {
+		return errors.WithMessage(err, "could not read current RLIMIT_NOFILE")
+	}
+
+	neu := old
+	neu.Cur = nofile
+	if neu.Max < nofile {
+		neu.Max = nofile
+	}
+	if err := syscall.Setrlimit(syscall.RLIMIT_NOFILE, &neu); err != nil {
+		return errors.WithMessage(err, "could not lower RLIMIT_NOFILE for builder sandbox")
+	}
+	defer syscall.Setrlimit(syscall.RLIMIT_NOFILE, &old) // nolint:errcheck
+
+	return fn()
+}
+
+// Wrap starts cmd under the sandbox's constraints: the process is launched
+// with RLIMIT_NOFILE already lowered if NoFile is set, the platform-specific
+// applier attaches it to its cgroup, and, if WallClockTimeout is set, a
+// watchdog escalates to SIGKILL when it's exceeded regardless of whether the
+// caller ever calls Instance.Stop. Output is captured the same way as an
+// unsandboxed invocation, via jsonLogs/sink, so sandboxing never forces a
+// builder to give up structured log capture.
+func (s *Sandbox) Wrap(cmd *exec.Cmd, jsonLogs bool, sink LogSink) (*Session, error) {
+	var sess *Session
+	err := withNoFileLimit(s.Config.NoFile, func() error {
+		var startErr error
+		sess, startErr = StartCaptured(s.Logger, cmd, jsonLogs, sink)
+		return startErr
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	applier := newSandboxApplier()
+	pid := cmd.Process.Pid
+
+	if err := applier.Apply(pid, s.Config); err != nil {
+		sess.TerminateThenKill(0)
+		return nil, errors.WithMessage(err, "could not sandbox builder process")
+	}
+
+	go func() {
+		<-sess.waitCh
+		if err := applier.Cleanup(pid); err != nil {
+			s.Logger.Warnw("could not clean up builder sandbox", "pid", pid, "error", err)
+		}
+	}()
+
+	if s.Config.WallClockTimeout > 0 {
+		go func() {
+			timer := time.NewTimer(s.Config.WallClockTimeout)
+			defer timer.Stop()
+			select {
+			case <-sess.waitCh:
+			case <-timer.C:
+				s.Logger.Warnw("builder exceeded wall-clock timeout, terminating", "timeout", s.Config.WallClockTimeout)
+				sess.Signal(syscall.SIGKILL)
+			}
+		}()
+	}
+
+	return sess, nil
+}
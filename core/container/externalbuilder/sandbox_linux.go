@@ -0,0 +1,69 @@
+//go:build linux
+// +build linux
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package externalbuilder
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+const cgroupRoot = "/sys/fs/cgroup"
+
+// cgroupSandbox enforces SandboxConfig via a dedicated cgroup v2 leaf
+// created per builder invocation.
+type cgroupSandbox struct{}
+
+func newSandboxApplier() sandboxApplier {
+	return cgroupSandbox{}
+}
+
+func (cgroupSandbox) Apply(pid int, cfg SandboxConfig) error {
+	group := filepath.Join(cgroupRoot, "fabric-externalbuilder", fmt.Sprintf("pid-%d", pid))
+	if err := os.MkdirAll(group, 0o755); err != nil {
+		return errors.WithMessage(err, "could not create cgroup for builder sandbox")
+	}
+
+	if cfg.CPUShares != 0 {
+		if err := writeCgroupFile(group, "cpu.weight", strconv.FormatUint(cfg.CPUShares, 10)); err != nil {
+			return err
+		}
+	}
+
+	if cfg.MemoryLimitBytes != 0 {
+		if err := writeCgroupFile(group, "memory.max", strconv.FormatUint(cfg.MemoryLimitBytes, 10)); err != nil {
+			return err
+		}
+	}
+
+	if err := writeCgroupFile(group, "cgroup.procs", strconv.Itoa(pid)); err != nil {
+		return errors.WithMessage(err, "could not attach builder process to cgroup")
+	}
+
+	return nil
+}
+
+// Cleanup removes the cgroup Apply created for pid. It is called once the
+// builder process has exited; the cgroup can only be removed once it has
+// no member processes left, which is guaranteed by that point.
+func (cgroupSandbox) Cleanup(pid int) error {
+	group := filepath.Join(cgroupRoot, "fabric-externalbuilder", fmt.Sprintf("pid-%d", pid))
+	if err := os.Remove(group); err != nil && !os.IsNotExist(err) {
+		return errors.WithMessage(err, "could not remove cgroup for builder sandbox")
+	}
+	return nil
+}
+
+func writeCgroupFile(group, name, value string) error {
+	return os.WriteFile(filepath.Join(group, name), []byte(value), 0o644)
+}
@@ -0,0 +1,27 @@
+//go:build !linux
+// +build !linux
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package externalbuilder
+
+// noopSandbox is used on platforms without cgroups v2. CPU and memory
+// limits are silently unenforced; WallClockTimeout and NoFile are still
+// applied by Sandbox.Wrap since those don't require cgroups.
+type noopSandbox struct{}
+
+func newSandboxApplier() sandboxApplier {
+	return noopSandbox{}
+}
+
+func (noopSandbox) Apply(pid int, cfg SandboxConfig) error {
+	return nil
+}
+
+func (noopSandbox) Cleanup(pid int) error {
+	return nil
+}
@@ -0,0 +1,152 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package externalbuilder
+
+import (
+	"io"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+)
+
+// Session represents a running invocation of an external builder or
+// launcher script. It allows callers to wait for completion and to
+// terminate the process if necessary.
+type Session struct {
+	mutex   sync.Mutex
+	command *exec.Cmd
+	logger  *flogging.FabricLogger
+	waitCh  chan struct{}
+	waitErr error
+	capture sync.WaitGroup
+
+	// Stdout and Stderr are populated by StartCaptured and record the
+	// process's output so it can be replayed (e.g. by Instance.Wait on a
+	// non-zero exit). They are nil for sessions created with Start.
+	Stdout *LineWriter
+	Stderr *LineWriter
+}
+
+// Start launches cmd, placing it in its own process group so that it (and
+// any children it spawns) can be signaled as a unit, and returns a Session
+// that can be used to wait for or terminate it.
+func Start(logger *flogging.FabricLogger, cmd *exec.Cmd) (*Session, error) {
+	return start(logger, cmd)
+}
+
+// StartCaptured behaves like Start, but additionally captures the
+// subprocess's stdout and stderr through LineWriters, parsing each line as
+// JSON when jsonLogs is set and forwarding it to sink. The resulting
+// Session's Stdout and Stderr fields hold the most recent captured lines.
+//
+// Unlike cmd.StdoutPipe/StderrPipe, cmd.Stdout/Stderr are set directly to
+// in-process io.Pipe writers: exec.Cmd's own Wait does not return until it
+// has finished copying the subprocess's output into them, so by the time
+// the process-reaping goroutine below observes an exited process, all of
+// its output is already flowing to (or through) the Forward goroutines -
+// closing the pipes at that point can't drop output still in flight, which
+// calling cmd.Wait() on a StdoutPipe/StderrPipe before they're drained can.
+func StartCaptured(logger *flogging.FabricLogger, cmd *exec.Cmd, jsonLogs bool, sink LogSink) (*Session, error) {
+	stdoutR, stdoutW := io.Pipe()
+	stderrR, stderrW := io.Pipe()
+	cmd.Stdout = stdoutW
+	cmd.Stderr = stderrW
+
+	s, err := start(logger, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	s.Stdout = &LineWriter{Logger: logger, Sink: sink, JSONLogs: jsonLogs}
+	s.Stderr = &LineWriter{Logger: logger, Sink: sink, JSONLogs: jsonLogs}
+
+	s.capture.Add(2)
+	go func() { defer s.capture.Done(); s.Stdout.Forward(stdoutR) }()
+	go func() { defer s.capture.Done(); s.Stderr.Forward(stderrR) }()
+
+	go func() {
+		<-s.waitCh
+		stdoutW.Close()
+		stderrW.Close()
+	}()
+
+	return s, nil
+}
+
+// start launches cmd and returns a Session wrapping it.
+func start(logger *flogging.FabricLogger, cmd *exec.Cmd) (*Session, error) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	s := &Session{
+		command: cmd,
+		logger:  logger,
+		waitCh:  make(chan struct{}),
+	}
+
+	go func() {
+		s.waitErr = s.command.Wait()
+		close(s.waitCh)
+	}()
+
+	return s, nil
+}
+
+// Wait blocks until the underlying process has exited and returns any error
+// reported by the operating system.
+func (s *Session) Wait() error {
+	<-s.waitCh
+	s.capture.Wait()
+	return s.waitErr
+}
+
+// ExitCode returns the exit code of the underlying process. It must only be
+// called after Wait has returned.
+func (s *Session) ExitCode() int {
+	if s.command.ProcessState == nil {
+		return -1
+	}
+	return s.command.ProcessState.ExitCode()
+}
+
+// Signal delivers sig to the process group of the underlying command.
+func (s *Session) Signal(sig syscall.Signal) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.command.Process == nil {
+		return
+	}
+	// The negative pid signals the whole process group that Start placed
+	// the command in, so children spawned by the builder are reaped too.
+	syscall.Kill(-s.command.Process.Pid, sig)
+}
+
+// TerminateThenKill sends SIGTERM to the process group and escalates to
+// SIGKILL if the process has not exited within termTimeout.
+func (s *Session) TerminateThenKill(termTimeout time.Duration) {
+	s.Signal(syscall.SIGTERM)
+
+	timer := time.NewTimer(termTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-s.waitCh:
+	case <-timer.C:
+		s.logger.Debugw("builder did not exit within TermTimeout, sending SIGKILL", "timeout", termTimeout)
+		s.Signal(syscall.SIGKILL)
+	}
+}